@@ -0,0 +1,88 @@
+package counter
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to a counter. It exists as an interface, rather than a bare
+// func() time.Time, so that independent counters can each be given their own simulated clock via
+// WithClock instead of all sharing - and racing on - the package-level Now variable.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts an ordinary func() time.Time into a Clock.
+type ClockFunc func() time.Time
+
+// Now returns f().
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// defaultClock is the Clock used by a counter when no WithClock option is supplied. It falls back
+// to the package-level Now variable, which is kept for one release as a deprecated path for
+// callers that haven't migrated to WithClock yet.
+type defaultClock struct{}
+
+func (defaultClock) Now() time.Time {
+	return Now()
+}
+
+// Option configures a counter constructor, such as NewExpiringCounter. See WithClock.
+type Option func(*options)
+
+type options struct {
+	clock Clock
+}
+
+func resolveOptions(opts []Option) *options {
+	o := &options{clock: defaultClock{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithClock configures a counter to get the current time from clock instead of from the
+// package-level Now variable. This lets tests give each counter its own simulated clock instead
+// of mutating shared global state.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// ManualClock is a Clock whose time is set explicitly rather than tracking wall-clock time,
+// intended for deterministic tests. It follows the pattern used by projects such as
+// CockroachDB's timeutil.ManualTime. ManualClock is safe for concurrent use.
+type ManualClock struct {
+	mux sync.Mutex
+	t   time.Time
+}
+
+// NewManualClock returns a pointer to a new ManualClock set to t.
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{t: t}
+}
+
+// Now returns the ManualClock's current time, as most recently set by Set or Advance.
+func (c *ManualClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.t
+}
+
+// Set changes the ManualClock's current time to t.
+func (c *ManualClock) Set(t time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.t = t
+}
+
+// Advance moves the ManualClock's current time forward by d. A negative d moves it backward.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,105 @@
+package counter_test
+
+import (
+	"testing"
+	"time"
+
+	counter "github.com/TylerHendrickson/go-hitcounter"
+)
+
+func TestExpiringCounter_WithClock(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	clock := counter.NewManualClock(start)
+
+	c, err := counter.NewExpiringCounter(time.Second*5, time.Second, counter.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+
+	c.AddHit()
+	clock.Advance(time.Second)
+	c.AddHit()
+	c.AddHit()
+
+	if got, want := c.GetHits(), uint64(3); got != want {
+		t.Errorf("expected %d hits but got %d", want, got)
+	}
+
+	clock.Set(start.Add(10 * time.Second))
+	if got, want := c.GetHits(), uint64(0); got != want {
+		t.Errorf("expected %d hits after advancing past the window but got %d", want, got)
+	}
+}
+
+func TestExpiringCounter_WithClock_independentOfOtherCounters(t *testing.T) {
+	clockA := counter.NewManualClock(time.Now())
+	clockB := counter.NewManualClock(time.Now())
+
+	a, err := counter.NewExpiringCounter(time.Second*5, time.Second, counter.WithClock(clockA))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	b, err := counter.NewExpiringCounter(time.Second*5, time.Second, counter.WithClock(clockB))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+
+	clockA.Advance(time.Hour)
+	a.AddHit()
+	b.AddHit()
+
+	if got := a.GetHits(); got != 1 {
+		t.Errorf("expected counter a to have 1 hit but got %d", got)
+	}
+	if got := b.GetHits(); got != 1 {
+		t.Errorf("expected counter b to have 1 hit but got %d", got)
+	}
+}
+
+func TestFlexibleHitCounter_WithClock(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	clock := counter.NewManualClock(start)
+
+	c, err := counter.NewFlexibleHitCounter(time.Second*5, time.Second, counter.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+
+	c.AddHit()
+	clock.Advance(time.Second)
+	c.AddHit()
+	c.AddHit()
+
+	if got, want := c.GetHits(), uint64(3); got != want {
+		t.Errorf("expected %d hits but got %d", want, got)
+	}
+
+	clock.Set(start.Add(10 * time.Second))
+	if got, want := c.GetHits(), uint64(0); got != want {
+		t.Errorf("expected %d hits after advancing past the window but got %d", want, got)
+	}
+}
+
+func TestImmediateHitCounter_WithClock(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	clock := counter.NewManualClock(start)
+
+	c, err := counter.NewImmediateHitCounter(time.Second*5, time.Second, counter.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+
+	c.AddHit()
+	clock.Advance(time.Second)
+	c.AddHit()
+	c.AddHit()
+
+	if got, want := c.GetHits(), uint64(3); got != want {
+		t.Errorf("expected %d hits but got %d", want, got)
+	}
+
+	clock.Set(start.Add(10 * time.Second))
+	if got, want := c.GetHits(), uint64(0); got != want {
+		t.Errorf("expected %d hits after advancing past the window but got %d", want, got)
+	}
+}
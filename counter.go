@@ -1,15 +1,23 @@
 package counter
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-// Now can be replaced to inject a different means of getting the current time
+// Now can be replaced to inject a different means of getting the current time.
+//
+// Deprecated: Now is a shared, package-level variable, so mutating it races any counter using it
+// concurrently and prevents different counters from running independent simulated clocks. Pass
+// WithClock to a counter's constructor instead. Now is kept for one release as a fallback used
+// only by counters that don't specify WithClock.
 var Now func() time.Time = time.Now
 
 // A slot tracks hits that occurred at a certain (resolved/rounded) timestamp.
@@ -59,11 +67,12 @@ var ErrInvalidDuration = errors.New("counter duration must be a multiple of its
 type ExpiringCounter struct {
 	slots slots
 	res   time.Duration
+	clock Clock
 	mux   sync.Mutex
 }
 
 func (c *ExpiringCounter) now() time.Time {
-	return Now().Truncate(c.res)
+	return c.clock.Now().Truncate(c.res)
 }
 
 func (c *ExpiringCounter) String() string {
@@ -82,13 +91,16 @@ func (c *ExpiringCounter) GetDuration() time.Duration {
 //
 // Example: NewExpiringCounter(5*time.Minute, time.Minute) creates a counter that tracks hits over
 // a rolling 5-minute period.
-func NewExpiringCounter(d time.Duration, r time.Duration) (*ExpiringCounter, error) {
+//
+// By default the counter gets the current time from the package-level Now variable; pass
+// WithClock to give it an independent Clock instead.
+func NewExpiringCounter(d time.Duration, r time.Duration, opts ...Option) (*ExpiringCounter, error) {
 	if d <= r || d%r != 0 {
 		return nil, ErrInvalidDuration
 	}
 
 	numSlots := d / r
-	c := &ExpiringCounter{slots: make([]*slot, numSlots), res: r}
+	c := &ExpiringCounter{slots: make([]*slot, numSlots), res: r, clock: resolveOptions(opts).clock}
 	fillTime := c.now()
 	for i := 0; i < int(numSlots); i++ {
 		c.slots[i] = NewSlot(fillTime)
@@ -124,21 +136,23 @@ func (c *ExpiringCounter) AddHit() {
 // is beyond the configured duration for the Counter.
 func (c *ExpiringCounter) AddHitAtTime(t time.Time) {
 	t = t.Truncate(c.res)
-	for i := c.maybeInsertSlot(t); i >= 0 && i < len(c.slots); i++ {
-		if s := c.slots[i]; s.time.Equal(t) {
-			s.AddHit()
-			break
-		}
+	if s := c.maybeInsertSlot(t); s != nil {
+		s.AddHit()
 	}
 }
 
-func (c *ExpiringCounter) maybeInsertSlot(t time.Time) int {
+// maybeInsertSlot returns the slot tracking time t, rotating the ring and inserting one at the
+// appropriate position if necessary, or nil if t is too old to be tracked. The slot is returned
+// while c.mux is still held: callers must use the returned *slot directly rather than re-deriving
+// it from c.slots by index afterwards, since a concurrent call can rotate the ring - and shift
+// every index - between maybeInsertSlot returning and any later unlocked read of c.slots.
+func (c *ExpiringCounter) maybeInsertSlot(t time.Time) *slot {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
 	if c.slots[0].time.Equal(t) {
 		// Insertion time is already the latest slot time
-		return 0
+		return c.slots[0]
 	}
 
 	if c.slots[0].time.Before(t) {
@@ -148,12 +162,20 @@ func (c *ExpiringCounter) maybeInsertSlot(t time.Time) int {
 			c.slots[i] = c.slots[i-1]
 		}
 		c.slots[0] = NewSlot(t)
-		return 0
+		return c.slots[0]
 	}
 
 	if t.Before(c.slots[len(c.slots)-1].time) {
 		// Given time is too old for the counter
-		return -1
+		return nil
+	}
+
+	// A slot for this exact time might already exist; reuse it instead of inserting a
+	// duplicate, which would silently shift-evict the true oldest slot and lose its hits.
+	for _, s := range c.slots {
+		if s.time.Equal(t) {
+			return s
+		}
 	}
 
 	// Figure out where to insert a new slot
@@ -172,5 +194,156 @@ func (c *ExpiringCounter) maybeInsertSlot(t time.Time) int {
 
 	// Insert the new slot
 	c.slots[insertPos] = NewSlot(t)
-	return insertPos
+	return c.slots[insertPos]
+}
+
+// snapshotMagic identifies the start of a binary snapshot produced by ExpiringCounter.MarshalBinary.
+var snapshotMagic = [3]byte{'g', 'h', 'c'}
+
+// snapshotVersion is the version of the binary snapshot format written by MarshalBinary. It's
+// bumped whenever the format changes incompatibly.
+const snapshotVersion byte = 1
+
+// ErrInvalidSnapshot is returned by UnmarshalBinary when data isn't a recognized snapshot.
+var ErrInvalidSnapshot = errors.New("counter: invalid snapshot")
+
+// ErrSnapshotMismatch is returned by UnmarshalBinary when data's resolution or duration disagree
+// with the counter it's being restored into.
+var ErrSnapshotMismatch = errors.New("counter: snapshot resolution/duration do not match counter")
+
+// MarshalBinary encodes the counter's current state - its resolution, duration, and each slot's
+// time and hit count - into a compact, versioned binary format suitable for restoring later via
+// UnmarshalBinary, e.g. to survive a process restart.
+func (c *ExpiringCounter) MarshalBinary() ([]byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	buf := make([]byte, 0, len(snapshotMagic)+1+2*binary.MaxVarintLen64+len(c.slots)*2*binary.MaxVarintLen64)
+	buf = append(buf, snapshotMagic[:]...)
+	buf = append(buf, snapshotVersion)
+	buf = appendVarint(buf, int64(c.res))
+	buf = appendVarint(buf, int64(c.GetDuration()))
+	for _, s := range c.slots {
+		buf = appendVarint(buf, s.time.UnixNano())
+		buf = appendUvarint(buf, s.hits)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary restores the counter's state from data previously produced by MarshalBinary.
+// Any restored slot older than now minus the counter's configured duration is dropped, and the
+// remaining slot times are re-truncated against the counter's current resolution. UnmarshalBinary
+// returns ErrSnapshotMismatch if data's resolution or duration disagree with the counter's own,
+// and ErrInvalidSnapshot if data isn't a recognized snapshot.
+func (c *ExpiringCounter) UnmarshalBinary(data []byte) error {
+	if len(data) < len(snapshotMagic)+1 || !bytes.Equal(data[:len(snapshotMagic)], snapshotMagic[:]) {
+		return ErrInvalidSnapshot
+	}
+	if data[len(snapshotMagic)] != snapshotVersion {
+		return ErrInvalidSnapshot
+	}
+
+	r := bytes.NewReader(data[len(snapshotMagic)+1:])
+	res, err := binary.ReadVarint(r)
+	if err != nil {
+		return ErrInvalidSnapshot
+	}
+	dur, err := binary.ReadVarint(r)
+	if err != nil {
+		return ErrInvalidSnapshot
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if time.Duration(res) != c.res || time.Duration(dur) != c.GetDuration() {
+		return ErrSnapshotMismatch
+	}
+
+	notValidBefore := c.now().Add(-c.res * time.Duration(len(c.slots)))
+	hitsByTime := make(map[int64]uint64, len(c.slots))
+	for {
+		t, err := binary.ReadVarint(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return ErrInvalidSnapshot
+		}
+		hits, err := binary.ReadUvarint(r)
+		if err != nil {
+			return ErrInvalidSnapshot
+		}
+
+		slotTime := time.Unix(0, t).Truncate(c.res)
+		if slotTime.Before(notValidBefore) {
+			continue
+		}
+		hitsByTime[slotTime.UnixNano()] += hits
+	}
+
+	fillTime := c.now()
+	restored := make(slots, len(c.slots))
+	for i := range restored {
+		s := NewSlot(fillTime)
+		s.hits = hitsByTime[fillTime.UnixNano()]
+		restored[i] = s
+		fillTime = fillTime.Add(-c.res)
+	}
+	c.slots = restored
+	return nil
+}
+
+// Snapshot returns a binary snapshot of the counter's state, as MarshalBinary.
+func (c *ExpiringCounter) Snapshot() ([]byte, error) {
+	return c.MarshalBinary()
+}
+
+// Restore replaces the counter's state with a snapshot previously produced by Snapshot, as
+// UnmarshalBinary.
+func (c *ExpiringCounter) Restore(data []byte) error {
+	return c.UnmarshalBinary(data)
+}
+
+// PersistEvery starts a goroutine that writes a snapshot of c to w every d, until stop is called.
+// It's meant to let a process restart without losing its rolling window, by periodically saving
+// state that can be fed back into Restore on the next startup. Errors returned by w.Write are
+// ignored, on the theory that a missed snapshot is preferable to crashing the process recording hits.
+func (c *ExpiringCounter) PersistEvery(d time.Duration, w io.Writer) (stop func()) {
+	ticker := time.NewTicker(d)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if snap, err := c.Snapshot(); err == nil {
+					_, _ = w.Write(snap)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// appendVarint appends the varint encoding of x to buf, returning the extended slice.
+func appendVarint(buf []byte, x int64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(scratch[:], x)
+	return append(buf, scratch[:n]...)
+}
+
+// appendUvarint appends the uvarint encoding of x to buf, returning the extended slice.
+func appendUvarint(buf []byte, x uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], x)
+	return append(buf, scratch[:n]...)
 }
@@ -62,22 +62,19 @@ func TestRollingTicksWithVariableHits(t *testing.T) {
 		testName := fmt.Sprintf("%d: expect %d hits in last %d seconds", ti, expected, tt.duration)
 
 		t.Run(testName, func(t *testing.T) {
-			restoreClockNow := counter.Now
-			t.Cleanup(func() { counter.Now = restoreClockNow })
-			startTime := counter.Now()
-			mockTime := startTime
-			counter.Now = func() time.Time {
-				return mockTime
-			}
+			startTime := time.Now()
+			clock := counter.NewManualClock(startTime)
 
-			c, err := counter.NewExpiringCounter(time.Second*time.Duration(tt.duration), time.Second)
+			c, err := counter.NewExpiringCounter(
+				time.Second*time.Duration(tt.duration), time.Second, counter.WithClock(clock),
+			)
 			if err != nil {
 				t.Errorf("Unexpected error: %s", err)
 				t.FailNow()
 			}
 
 			for offset, hits := range tt.hitsPerTick {
-				mockTime = startTime.Add(time.Duration(offset) * time.Second)
+				clock.Set(startTime.Add(time.Duration(offset) * time.Second))
 				for i := uint64(0); i < hits; i++ {
 					c.AddHit()
 				}
@@ -102,11 +99,9 @@ func TestOutOfOrderHits(t *testing.T) {
 		expected := uint64Sum(tt.hitsPerTick[len(tt.hitsPerTick)-tt.duration : len(tt.hitsPerTick)])
 		testName := fmt.Sprintf("%d: expect %d hits in last %d seconds", ti, expected, tt.duration)
 		t.Run(testName, func(t *testing.T) {
-			restoreClockNow := counter.Now
-			t.Cleanup(func() { counter.Now = restoreClockNow })
-			mockTime := counter.Now().Truncate(time.Second)
+			mockTime := time.Now().Truncate(time.Second)
 			simTime := mockTime.Add(-time.Duration(len(tt.hitsPerTick)) * time.Second)
-			counter.Now = func() time.Time { return mockTime }
+			clock := counter.NewManualClock(mockTime)
 
 			hitMoments := make([]time.Time, 0)
 			for _, numHits := range tt.hitsPerTick {
@@ -117,7 +112,9 @@ func TestOutOfOrderHits(t *testing.T) {
 			}
 			shuffleTimes(hitMoments)
 
-			c, err := counter.NewExpiringCounter(time.Second*time.Duration(tt.duration), time.Second)
+			c, err := counter.NewExpiringCounter(
+				time.Second*time.Duration(tt.duration), time.Second, counter.WithClock(clock),
+			)
 			if err != nil {
 				t.Errorf("Unexpected error: %s", err)
 				t.FailNow()
@@ -0,0 +1,158 @@
+// Package httpratelimit provides an http.Handler middleware, and a standalone Limiter, that use
+// a github.com/TylerHendrickson/go-hitcounter HitCounter per key to throttle callers once they
+// exceed a configured number of hits within a rolling window.
+package httpratelimit
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HitCounter is the subset of counter.ExpiringCounter's and counter.FlexibleHitCounter's methods
+// that Limiter needs. Both types, and any other type with this method set, satisfy it.
+type HitCounter interface {
+	AddHit()
+	GetHits() uint64
+	GetDuration() time.Duration
+}
+
+// NewCounterFunc constructs the HitCounter used to track hits for a single key. It's called once
+// per distinct key the Limiter observes; implementations typically close over fixed duration and
+// resolution values and call counter.NewExpiringCounter or counter.NewFlexibleHitCounter.
+type NewCounterFunc func() (HitCounter, error)
+
+// KeyFunc extracts the rate-limiting key from an incoming request, e.g. the client's address or
+// an API key header.
+type KeyFunc func(*http.Request) string
+
+// ByClientIP is a KeyFunc that keys on r.RemoteAddr.
+func ByClientIP(r *http.Request) string {
+	return r.RemoteAddr
+}
+
+// ByHeader returns a KeyFunc that keys on the value of the named request header.
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Limiter tracks one HitCounter per key and reports whether a key is within its configured quota.
+// The number of distinct keys tracked at once is bounded by MaxKeys; once that bound is exceeded,
+// the least-recently-used key is evicted. Limiter is safe for concurrent use and can be used
+// outside of HTTP entirely via Allow.
+type Limiter struct {
+	// Threshold is the maximum number of hits a key may accrue within its counter's rolling
+	// window before Allow reports false for it.
+	Threshold uint64
+	// Resolution is the resolution of the counters produced by NewCounter. It's used as a floor
+	// on the Retry-After header written by the HTTP middleware, since a key's hit count can't
+	// change any faster than its counter's resolution allows.
+	Resolution time.Duration
+	// MaxKeys bounds the number of distinct keys tracked at once. Zero means unbounded.
+	MaxKeys int
+	// NewCounter constructs the HitCounter used for each newly-observed key.
+	NewCounter NewCounterFunc
+
+	mux   sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type limiterEntry struct {
+	key       string
+	counter   HitCounter
+	createdAt time.Time
+}
+
+// Allow records a hit for key and reports whether key is still within its configured quota.
+// A key whose counter could not be created (see NewCounter) is always allowed, since failing
+// open is preferable to rejecting every request for a key the Limiter can't track.
+func (l *Limiter) Allow(key string) bool {
+	c := l.counterFor(key)
+	if c == nil {
+		return true
+	}
+	c.AddHit()
+	return c.GetHits() <= l.Threshold
+}
+
+// Middleware returns an http.Handler that calls next for requests within quota, and otherwise
+// responds with 429 Too Many Requests and a Retry-After header. keyFunc determines the
+// rate-limiting key for each request; ByClientIP is used if keyFunc is nil.
+func (l *Limiter) Middleware(keyFunc KeyFunc, next http.Handler) http.Handler {
+	if keyFunc == nil {
+		keyFunc = ByClientIP
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := keyFunc(r)
+		if l.Allow(key) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		retryAfter := l.retryAfter(key)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+	})
+}
+
+// retryAfter reports how long a caller rate-limited on key should wait before retrying, based on
+// how much of key's counter window remains since its entry was created. Once that much time has
+// passed, every hit that counted toward the limit will have rolled off the counter's window, so
+// retrying is guaranteed to no longer be rejected on account of those hits. The result is never
+// less than Resolution, since a key's hit count can't change any faster than that.
+func (l *Limiter) retryAfter(key string) time.Duration {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return l.Resolution
+	}
+
+	e := el.Value.(*limiterEntry)
+	remaining := e.counter.GetDuration() - time.Since(e.createdAt)
+	if remaining < l.Resolution {
+		return l.Resolution
+	}
+	return remaining
+}
+
+// counterFor returns the HitCounter tracked for key, creating one via NewCounter and evicting the
+// least-recently-used key if MaxKeys would otherwise be exceeded.
+func (l *Limiter) counterFor(key string) HitCounter {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.ll == nil {
+		l.ll = list.New()
+		l.items = make(map[string]*list.Element)
+	}
+
+	if el, ok := l.items[key]; ok {
+		l.ll.MoveToFront(el)
+		return el.Value.(*limiterEntry).counter
+	}
+
+	c, err := l.NewCounter()
+	if err != nil {
+		return nil
+	}
+
+	el := l.ll.PushFront(&limiterEntry{key: key, counter: c, createdAt: time.Now()})
+	l.items[key] = el
+
+	if l.MaxKeys > 0 && l.ll.Len() > l.MaxKeys {
+		oldest := l.ll.Back()
+		if oldest != nil {
+			l.ll.Remove(oldest)
+			delete(l.items, oldest.Value.(*limiterEntry).key)
+		}
+	}
+
+	return c
+}
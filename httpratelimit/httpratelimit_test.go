@@ -0,0 +1,117 @@
+package httpratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	counter "github.com/TylerHendrickson/go-hitcounter"
+	"github.com/TylerHendrickson/go-hitcounter/httpratelimit"
+)
+
+func newTestLimiter(threshold uint64, maxKeys int) *httpratelimit.Limiter {
+	return &httpratelimit.Limiter{
+		Threshold:  threshold,
+		Resolution: time.Second,
+		MaxKeys:    maxKeys,
+		NewCounter: func() (httpratelimit.HitCounter, error) {
+			return counter.NewExpiringCounter(time.Minute, time.Second)
+		},
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l := newTestLimiter(2, 0)
+
+	for i, want := range []bool{true, true, false, false} {
+		if got := l.Allow("alice"); got != want {
+			t.Errorf("hit %d: expected Allow(%q) = %v but got %v", i, "alice", want, got)
+		}
+	}
+
+	if !l.Allow("bob") {
+		t.Errorf("expected a different key to be unaffected by alice's quota")
+	}
+}
+
+func TestLimiter_Allow_failsOpenWhenCounterCannotBeCreated(t *testing.T) {
+	l := &httpratelimit.Limiter{
+		Threshold: 1,
+		NewCounter: func() (httpratelimit.HitCounter, error) {
+			return nil, errors.New("boom")
+		},
+	}
+
+	if !l.Allow("alice") {
+		t.Error("expected Allow to fail open when NewCounter returns an error")
+	}
+}
+
+func TestLimiter_evictsLeastRecentlyUsedKey(t *testing.T) {
+	l := newTestLimiter(10, 1)
+
+	l.Allow("alice")
+	l.Allow("bob")
+
+	// alice's counter should have been evicted in favor of bob's, so a fresh counter is created
+	// and alice should be allowed exactly as many times as a brand new key would be.
+	for i := 0; i < 10; i++ {
+		if !l.Allow("alice") {
+			t.Fatalf("hit %d: expected alice to be within quota after eviction", i)
+		}
+	}
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	l := newTestLimiter(1, 0)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := l.Middleware(httpratelimit.ByClientIP, next)
+
+	for i, wantStatus := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != wantStatus {
+			t.Errorf("request %d: expected status %d but got %d", i, wantStatus, rec.Code)
+		}
+		if i > 0 && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("request %d: expected a Retry-After header on rejected request", i)
+		}
+	}
+}
+
+func TestLimiter_Middleware_RetryAfterReflectsRemainingWindow(t *testing.T) {
+	l := &httpratelimit.Limiter{
+		Threshold:  1,
+		Resolution: time.Second,
+		NewCounter: func() (httpratelimit.HitCounter, error) {
+			return counter.NewExpiringCounter(5*time.Second, time.Second)
+		},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := l.Middleware(httpratelimit.ByClientIP, next)
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		return req
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest())
+
+	// Almost no time has passed since the key's counter was created, so nearly the entire
+	// 5-second window remains - Retry-After should reflect that, not a flat 1-second resolution.
+	if got := rec.Header().Get("Retry-After"); got != "5" {
+		t.Errorf("expected Retry-After %q but got %q", "5", got)
+	}
+}
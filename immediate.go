@@ -1,6 +1,9 @@
-package hitcounter
+package counter
 
 import (
+	"bytes"
+	"encoding/binary"
+	"io"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,11 +14,12 @@ import (
 type ImmediateHitCounter struct {
 	slots slots
 	res   time.Duration
+	clock Clock
 	mux   sync.Mutex
 }
 
 func (c *ImmediateHitCounter) now() time.Time {
-	return Now().Truncate(c.res)
+	return c.clock.Now().Truncate(c.res)
 }
 
 func (c *ImmediateHitCounter) String() string {
@@ -34,13 +38,16 @@ func (c *ImmediateHitCounter) GetDuration() time.Duration {
 //
 // Example: NewImmediateHitCounter(5*time.Minute, time.Minute) creates a counter that tracks hits over
 // a rolling 5-minute period.
-func NewImmediateHitCounter(d time.Duration, r time.Duration) (*ImmediateHitCounter, error) {
+//
+// By default the counter gets the current time from the package-level Now variable; pass
+// WithClock to give it an independent Clock instead.
+func NewImmediateHitCounter(d time.Duration, r time.Duration, opts ...Option) (*ImmediateHitCounter, error) {
 	if d <= r || d%r != 0 {
 		return nil, ErrInvalidDuration
 	}
 
 	numSlots := d / r
-	c := &ImmediateHitCounter{slots: make([]*slot, numSlots), res: r}
+	c := &ImmediateHitCounter{slots: make([]*slot, numSlots), res: r, clock: resolveOptions(opts).clock}
 	fillTime := c.now()
 	for i := 0; i < int(numSlots); i++ {
 		c.slots[i] = &slot{time: fillTime}
@@ -85,3 +92,126 @@ func (c *ImmediateHitCounter) maybeShiftIn(t time.Time) {
 		c.slots[0] = &slot{time: t, hits: 0}
 	}
 }
+
+// MarshalBinary encodes the counter's current state - its resolution, duration, and each slot's
+// time and hit count - into a compact, versioned binary format suitable for restoring later via
+// UnmarshalBinary, e.g. to survive a process restart.
+func (c *ImmediateHitCounter) MarshalBinary() ([]byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	buf := make([]byte, 0, len(snapshotMagic)+1+2*binary.MaxVarintLen64+len(c.slots)*2*binary.MaxVarintLen64)
+	buf = append(buf, snapshotMagic[:]...)
+	buf = append(buf, snapshotVersion)
+	buf = appendVarint(buf, int64(c.res))
+	buf = appendVarint(buf, int64(c.GetDuration()))
+	for _, s := range c.slots {
+		buf = appendVarint(buf, s.time.UnixNano())
+		buf = appendUvarint(buf, s.hits)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary restores the counter's state from data previously produced by MarshalBinary.
+// Any restored slot older than now minus the counter's configured duration is dropped, and the
+// remaining slot times are re-truncated against the counter's current resolution. UnmarshalBinary
+// returns ErrSnapshotMismatch if data's resolution or duration disagree with the counter's own,
+// and ErrInvalidSnapshot if data isn't a recognized snapshot.
+func (c *ImmediateHitCounter) UnmarshalBinary(data []byte) error {
+	if len(data) < len(snapshotMagic)+1 || !bytes.Equal(data[:len(snapshotMagic)], snapshotMagic[:]) {
+		return ErrInvalidSnapshot
+	}
+	if data[len(snapshotMagic)] != snapshotVersion {
+		return ErrInvalidSnapshot
+	}
+
+	r := bytes.NewReader(data[len(snapshotMagic)+1:])
+	res, err := binary.ReadVarint(r)
+	if err != nil {
+		return ErrInvalidSnapshot
+	}
+	dur, err := binary.ReadVarint(r)
+	if err != nil {
+		return ErrInvalidSnapshot
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if time.Duration(res) != c.res || time.Duration(dur) != c.GetDuration() {
+		return ErrSnapshotMismatch
+	}
+
+	notValidBefore := c.now().Add(-c.res * time.Duration(len(c.slots)))
+	hitsByTime := make(map[int64]uint64, len(c.slots))
+	for {
+		t, err := binary.ReadVarint(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return ErrInvalidSnapshot
+		}
+		hits, err := binary.ReadUvarint(r)
+		if err != nil {
+			return ErrInvalidSnapshot
+		}
+
+		slotTime := time.Unix(0, t).Truncate(c.res)
+		if slotTime.Before(notValidBefore) {
+			continue
+		}
+		hitsByTime[slotTime.UnixNano()] += hits
+	}
+
+	fillTime := c.now()
+	restored := make(slots, len(c.slots))
+	for i := range restored {
+		s := &slot{time: fillTime}
+		s.hits = hitsByTime[fillTime.UnixNano()]
+		restored[i] = s
+		fillTime = fillTime.Add(-c.res)
+	}
+	c.slots = restored
+	return nil
+}
+
+// Snapshot returns a binary snapshot of the counter's state, as MarshalBinary.
+func (c *ImmediateHitCounter) Snapshot() ([]byte, error) {
+	return c.MarshalBinary()
+}
+
+// Restore replaces the counter's state with a snapshot previously produced by Snapshot, as
+// UnmarshalBinary.
+func (c *ImmediateHitCounter) Restore(data []byte) error {
+	return c.UnmarshalBinary(data)
+}
+
+// PersistEvery starts a goroutine that writes a snapshot of c to w every d, until stop is called.
+// It's meant to let a process restart without losing its rolling window, by periodically saving
+// state that can be fed back into Restore on the next startup. Errors returned by w.Write are
+// ignored, on the theory that a missed snapshot is preferable to crashing the process recording hits.
+func (c *ImmediateHitCounter) PersistEvery(d time.Duration, w io.Writer) (stop func()) {
+	ticker := time.NewTicker(d)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if snap, err := c.Snapshot(); err == nil {
+					_, _ = w.Write(snap)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,108 @@
+package counter
+
+import (
+	"errors"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultShardCount is the number of shards a ShardedCounter uses when none is specified,
+// chosen to match the default used by similar sharded caches (e.g. freecache) as a balance
+// between lock contention and memory overhead.
+const DefaultShardCount = 256
+
+// ErrInvalidShardCount is returned by NewShardedCounter when n is negative.
+var ErrInvalidShardCount = errors.New("counter: shard count must not be negative")
+
+// ShardedCounter is a HitCounter-like type that spreads hits across many independent
+// ExpiringCounter shards, each guarded by its own mutex. Unlike ExpiringCounter, FlexibleHitCounter,
+// and ImmediateHitCounter, which serialize every AddHit/AddHitAtTime call through a single mutex,
+// ShardedCounter only contends callers that happen to land on the same shard, which makes it
+// better suited to high-concurrency scenarios such as a webserver recording a hit per request.
+type ShardedCounter struct {
+	shards []*ExpiringCounter
+	next   uint64 // round-robin cursor used to spread hits that have no caller-supplied key
+}
+
+// NewShardedCounter returns a pointer to a new ShardedCounter with n shards, each an
+// ExpiringCounter configured with rolling expiration window duration d and resolution r.
+// If n is 0, DefaultShardCount is used. If n is negative, ErrInvalidShardCount is returned.
+// See NewExpiringCounter for the constraints on d and r; the same error is returned here if
+// they're violated.
+func NewShardedCounter(n int, d, r time.Duration) (*ShardedCounter, error) {
+	if n < 0 {
+		return nil, ErrInvalidShardCount
+	}
+	if n == 0 {
+		n = DefaultShardCount
+	}
+
+	shards := make([]*ExpiringCounter, n)
+	for i := range shards {
+		s, err := NewExpiringCounter(d, r)
+		if err != nil {
+			return nil, err
+		}
+		shards[i] = s
+	}
+	return &ShardedCounter{shards: shards}, nil
+}
+
+// GetDuration returns the configured duration of the ShardedCounter's shards.
+func (c *ShardedCounter) GetDuration() time.Duration {
+	return c.shards[0].GetDuration()
+}
+
+// AddHit atomically increments the number of hits for the current time on a shard chosen by
+// round-robin. Because there's no caller-supplied key to hash on, round-robin is used instead
+// to spread load evenly across shards.
+func (c *ShardedCounter) AddHit() {
+	c.nextShard().AddHit()
+}
+
+// AddHitAtTime is like AddHit, but takes a discrete time instead of inferring the time of the
+// hit based on the current time. See ExpiringCounter.AddHitAtTime for caveats.
+func (c *ShardedCounter) AddHitAtTime(t time.Time) {
+	c.nextShard().AddHitAtTime(t)
+}
+
+// AddHitForKey is like AddHit, but dispatches to the shard determined by hashing key, so that
+// repeated hits for the same key are always tracked by the same shard.
+func (c *ShardedCounter) AddHitForKey(key []byte) {
+	c.shardForKey(key).AddHit()
+}
+
+// AddHitForKeyAtTime combines the behaviors of AddHitForKey and AddHitAtTime.
+func (c *ShardedCounter) AddHitForKeyAtTime(key []byte, t time.Time) {
+	c.shardForKey(key).AddHitAtTime(t)
+}
+
+// GetHits returns the total number of hits recorded across all shards within the configured
+// duration.
+func (c *ShardedCounter) GetHits() (total uint64) {
+	for _, s := range c.shards {
+		total += s.GetHits()
+	}
+	return
+}
+
+// GetHitsForKey returns the number of hits recorded within the configured duration by the shard
+// that key hashes to, reading only that shard. The count includes the hits of every other key
+// assigned to the same shard, as well as keyless hits (AddHit, AddHitAtTime) that landed on it.
+func (c *ShardedCounter) GetHitsForKey(key []byte) uint64 {
+	return c.shardForKey(key).GetHits()
+}
+
+// nextShard returns the next shard in round-robin order, used for hits with no caller-supplied key.
+func (c *ShardedCounter) nextShard() *ExpiringCounter {
+	i := atomic.AddUint64(&c.next, 1)
+	return c.shards[i%uint64(len(c.shards))]
+}
+
+// shardForKey returns the shard that key is assigned to.
+func (c *ShardedCounter) shardForKey(key []byte) *ExpiringCounter {
+	h := fnv.New64a()
+	h.Write(key)
+	return c.shards[h.Sum64()%uint64(len(c.shards))]
+}
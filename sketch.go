@@ -0,0 +1,202 @@
+package counter
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInvalidSketchParams is returned by NewSketchCounter when epsilon or delta are out of range.
+var ErrInvalidSketchParams = errors.New("counter: epsilon and delta must be in the range (0, 1)")
+
+// sketchSlot is a time-bounded count-min sketch: a depth x width matrix of counters, where depth
+// is the number of independent hash functions and width is the number of counters per hash
+// function. It plays the same role in SketchCounter that slot plays in ExpiringCounter, except
+// that it tracks approximate per-key counts instead of a single scalar.
+type sketchSlot struct {
+	time   time.Time
+	matrix [][]uint32 // depth rows of width uint32 counters
+}
+
+func newSketchSlot(t time.Time, depth, width int) *sketchSlot {
+	matrix := make([][]uint32, depth)
+	for i := range matrix {
+		matrix[i] = make([]uint32, width)
+	}
+	return &sketchSlot{time: t, matrix: matrix}
+}
+
+func (s *sketchSlot) addHit(cols []uint32) {
+	for i, col := range cols {
+		atomic.AddUint32(&s.matrix[i][col], 1)
+	}
+}
+
+// estimate returns this slot's count-min estimate for a key whose hash-function columns are cols.
+func (s *sketchSlot) estimate(cols []uint32) uint32 {
+	min := s.matrix[0][cols[0]]
+	for i := 1; i < len(cols); i++ {
+		if v := s.matrix[i][cols[i]]; v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// SketchCounter approximately tracks hits per key across a keyspace too large to give each key
+// its own counter, by combining ExpiringCounter's ring-of-slots design with a count-min sketch:
+// each time slot holds a depth x width matrix of counters instead of a single scalar. Estimates
+// returned by GetHitsForKey never underestimate the true count, and overestimate it by at most
+// epsilon * (total hits recorded in the window) with probability 1-delta.
+type SketchCounter struct {
+	slots        []*sketchSlot
+	res          time.Duration
+	depth, width int
+	seeds        []uint32
+	mux          sync.Mutex
+}
+
+// NewSketchCounter returns a pointer to a new SketchCounter with a rolling expiration window
+// duration d and resolution r (see NewExpiringCounter for their constraints), sized so that
+// GetHitsForKey overestimates the true hit count for a key by at most epsilon * total hits with
+// probability 1-delta. The sketch's width is ceil(e/epsilon) and its depth is ceil(ln(1/delta)).
+func NewSketchCounter(d, r time.Duration, epsilon, delta float64) (*SketchCounter, error) {
+	if d <= r || d%r != 0 {
+		return nil, ErrInvalidDuration
+	}
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		return nil, ErrInvalidSketchParams
+	}
+
+	width := int(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+
+	seeds := make([]uint32, depth)
+	for i := range seeds {
+		// Arbitrary but fixed per-row seeds, spread out so each hash function behaves
+		// independently of the others.
+		seeds[i] = uint32(i)*0x9e3779b9 + 1
+	}
+
+	numSlots := d / r
+	c := &SketchCounter{res: r, depth: depth, width: width, seeds: seeds, slots: make([]*sketchSlot, numSlots)}
+	fillTime := c.now()
+	for i := 0; i < int(numSlots); i++ {
+		c.slots[i] = newSketchSlot(fillTime, depth, width)
+		fillTime = fillTime.Add(-r)
+	}
+	return c, nil
+}
+
+func (c *SketchCounter) now() time.Time {
+	return Now().Truncate(c.res)
+}
+
+// GetDuration returns the configured duration of the SketchCounter.
+func (c *SketchCounter) GetDuration() time.Duration {
+	return time.Duration(len(c.slots)) * c.res
+}
+
+// AddHitForKey records a hit for key at the current time.
+func (c *SketchCounter) AddHitForKey(key []byte) {
+	c.AddHitForKeyAtTime(key, c.now())
+}
+
+// AddHitForKeyAtTime is like AddHitForKey, but takes a discrete time instead of inferring the
+// time of the hit based on the current time. See ExpiringCounter.AddHitAtTime for caveats.
+func (c *SketchCounter) AddHitForKeyAtTime(key []byte, t time.Time) {
+	t = t.Truncate(c.res)
+	if s := c.maybeInsertSlot(t); s != nil {
+		s.addHit(c.columnsFor(key))
+	}
+}
+
+// GetHitsForKey returns the estimated number of hits recorded for key within the counter's
+// configured duration. The estimate never undercounts, and overcounts by at most
+// epsilon * (total hits in the window) with probability 1-delta, per NewSketchCounter's epsilon
+// and delta parameters.
+func (c *SketchCounter) GetHitsForKey(key []byte) (total uint64) {
+	notValidBefore := c.now().Add(-c.res * time.Duration(len(c.slots)))
+	cols := c.columnsFor(key)
+	for _, s := range c.slots {
+		if !s.time.Before(notValidBefore) {
+			total += uint64(s.estimate(cols))
+		}
+	}
+	return
+}
+
+// columnsFor returns, for each of the sketch's depth hash functions, the column that key hashes to.
+func (c *SketchCounter) columnsFor(key []byte) []uint32 {
+	cols := make([]uint32, c.depth)
+	for i, seed := range c.seeds {
+		cols[i] = hashWithSeed(key, seed) % uint32(c.width)
+	}
+	return cols
+}
+
+// maybeInsertSlot is the count-min-sketch analog of ExpiringCounter.maybeInsertSlot: it rotates
+// the ring of slots so that one exists for time t, zeroing the incoming slot's matrix, and
+// returns that slot (or nil if t is too old to be tracked). The slot is returned while c.mux is
+// still held, so callers must not read it after maybeInsertSlot returns without still holding a
+// reference obtained here - they must not re-derive it from c.slots by index, since a concurrent
+// call can rotate the ring (and shift indices) between maybeInsertSlot returning and any later
+// read of c.slots.
+func (c *SketchCounter) maybeInsertSlot(t time.Time) *sketchSlot {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.slots[0].time.Equal(t) {
+		return c.slots[0]
+	}
+
+	if c.slots[0].time.Before(t) {
+		for i := len(c.slots) - 1; i > 0; i-- {
+			c.slots[i] = c.slots[i-1]
+		}
+		c.slots[0] = newSketchSlot(t, c.depth, c.width)
+		return c.slots[0]
+	}
+
+	if t.Before(c.slots[len(c.slots)-1].time) {
+		return nil
+	}
+
+	// A slot for this exact time might already exist; reuse it instead of inserting a
+	// duplicate, which would silently shift-evict the true oldest slot and lose its hits.
+	for _, s := range c.slots {
+		if s.time.Equal(t) {
+			return s
+		}
+	}
+
+	insertPos := 1
+	for insertPos < len(c.slots)-1 {
+		if t.After(c.slots[insertPos].time) {
+			break
+		}
+		insertPos++
+	}
+
+	for i := len(c.slots) - 1; i > insertPos; i-- {
+		c.slots[i] = c.slots[i-1]
+	}
+
+	c.slots[insertPos] = newSketchSlot(t, c.depth, c.width)
+	return c.slots[insertPos]
+}
+
+// hashWithSeed returns a hash of key that's independent, for distinct seeds, of hashes produced
+// for other seeds.
+func hashWithSeed(key []byte, seed uint32) uint32 {
+	h := fnv.New32a()
+	var seedBuf [4]byte
+	binary.BigEndian.PutUint32(seedBuf[:], seed)
+	h.Write(seedBuf[:])
+	h.Write(key)
+	return h.Sum32()
+}
@@ -0,0 +1,116 @@
+package counter_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	counter "github.com/TylerHendrickson/go-hitcounter"
+)
+
+func TestSketchCounter_GetHitsForKey(t *testing.T) {
+	c, err := counter.NewSketchCounter(time.Minute, time.Second, 0.01, 0.01)
+	if err != nil {
+		t.Fatalf("unexpected error creating new sketch counter: %s", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		c.AddHitForKey([]byte("alice"))
+	}
+	for i := 0; i < 3; i++ {
+		c.AddHitForKey([]byte("bob"))
+	}
+
+	// A count-min sketch never underestimates.
+	if got := c.GetHitsForKey([]byte("alice")); got < 10 {
+		t.Errorf("expected at least 10 hits for key %q but got %d", "alice", got)
+	}
+	if got := c.GetHitsForKey([]byte("bob")); got < 3 {
+		t.Errorf("expected at least 3 hits for key %q but got %d", "bob", got)
+	}
+	if got := c.GetHitsForKey([]byte("carol")); got != 0 {
+		t.Errorf("expected 0 hits for an untouched key but got %d", got)
+	}
+}
+
+func TestSketchCounter_OutOfOrderHitToMiddleSlotDoesNotEvictOldestSlot(t *testing.T) {
+	restoreClockNow := counter.Now
+	t.Cleanup(func() { counter.Now = restoreClockNow })
+	now := counter.Now().Truncate(time.Second)
+	counter.Now = func() time.Time { return now }
+
+	c, err := counter.NewSketchCounter(time.Second*5, time.Second, 0.01, 0.01)
+	if err != nil {
+		t.Fatalf("unexpected error creating new sketch counter: %s", err)
+	}
+
+	oldest := now.Add(-4 * time.Second)
+	c.AddHitForKeyAtTime([]byte("alice"), oldest)
+	if got := c.GetHitsForKey([]byte("alice")); got < 1 {
+		t.Fatalf("expected at least 1 hit for the oldest slot before the out-of-order hit, got %d", got)
+	}
+
+	// A hit landing on a middle slot's exact time must reuse that slot, not insert a duplicate
+	// that shift-evicts the true oldest slot.
+	middle := now.Add(-1 * time.Second)
+	c.AddHitForKeyAtTime([]byte("bob"), middle)
+
+	if got := c.GetHitsForKey([]byte("alice")); got < 1 {
+		t.Errorf("expected the oldest slot's hit to survive an out-of-order hit to a middle slot, got %d", got)
+	}
+}
+
+func TestNewSketchCounter_InvalidParams(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		epsilon, delta float64
+	}{
+		{"zero epsilon", 0, 0.01},
+		{"zero delta", 0.01, 0},
+		{"epsilon out of range", 1.5, 0.01},
+		{"delta out of range", 0.01, 1.5},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := counter.NewSketchCounter(time.Minute, time.Second, tt.epsilon, tt.delta); err != counter.ErrInvalidSketchParams {
+				t.Errorf("expected %q but got %v", counter.ErrInvalidSketchParams, err)
+			}
+		})
+	}
+}
+
+// BenchmarkSketchCounter_Memory and BenchmarkShardedCounter_Memory report allocated bytes for
+// tracking a given number of distinct keys, to compare SketchCounter's memory against a
+// ShardedCounter's. BenchmarkShardedCounter_Memory covers 1,000 and 100,000 keys only.
+func BenchmarkSketchCounter_Memory(b *testing.B) {
+	for _, numKeys := range []int{1_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("%d keys", numKeys), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c, err := counter.NewSketchCounter(time.Minute, time.Second, 0.001, 0.01)
+				if err != nil {
+					b.Fatalf("unexpected error creating new sketch counter: %s", err)
+				}
+				for k := 0; k < numKeys; k++ {
+					c.AddHitForKey([]byte(fmt.Sprintf("key-%d", k)))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkShardedCounter_Memory(b *testing.B) {
+	for _, numKeys := range []int{1_000, 100_000} {
+		b.Run(fmt.Sprintf("%d keys", numKeys), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c, err := counter.NewShardedCounter(counter.DefaultShardCount, time.Minute, time.Second)
+				if err != nil {
+					b.Fatalf("unexpected error creating new sharded counter: %s", err)
+				}
+				for k := 0; k < numKeys; k++ {
+					c.AddHitForKey([]byte(fmt.Sprintf("key-%d", k)))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package counter_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	counter "github.com/TylerHendrickson/go-hitcounter"
+)
+
+func TestExpiringCounter_SnapshotRestore(t *testing.T) {
+	restoreClockNow := counter.Now
+	t.Cleanup(func() { counter.Now = restoreClockNow })
+	mockTime := counter.Now().Truncate(time.Second)
+	counter.Now = func() time.Time { return mockTime }
+
+	c, err := counter.NewExpiringCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		c.AddHit()
+	}
+	mockTime = mockTime.Add(time.Second)
+	c.AddHit()
+
+	snap, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error taking snapshot: %s", err)
+	}
+
+	restored, err := counter.NewExpiringCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("unexpected error restoring snapshot: %s", err)
+	}
+
+	if got, want := restored.GetHits(), c.GetHits(); got != want {
+		t.Errorf("expected restored counter to have %d hits but got %d", want, got)
+	}
+}
+
+func TestExpiringCounter_RestoreRejectsMismatchedConfig(t *testing.T) {
+	c, err := counter.NewExpiringCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	snap, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error taking snapshot: %s", err)
+	}
+
+	other, err := counter.NewExpiringCounter(time.Second*10, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := other.Restore(snap); err != counter.ErrSnapshotMismatch {
+		t.Errorf("expected %q but got %v", counter.ErrSnapshotMismatch, err)
+	}
+}
+
+func TestExpiringCounter_RestoreRejectsInvalidData(t *testing.T) {
+	c, err := counter.NewExpiringCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := c.Restore([]byte("not a snapshot")); err != counter.ErrInvalidSnapshot {
+		t.Errorf("expected %q but got %v", counter.ErrInvalidSnapshot, err)
+	}
+}
+
+func TestExpiringCounter_PersistEvery(t *testing.T) {
+	c, err := counter.NewExpiringCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	c.AddHit()
+
+	var buf bytes.Buffer
+	stop := c.PersistEvery(time.Millisecond, &buf)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+
+	if buf.Len() == 0 {
+		t.Error("expected PersistEvery to have written at least one snapshot")
+	}
+}
+
+func TestFlexibleHitCounter_SnapshotRestore(t *testing.T) {
+	clock := counter.NewManualClock(time.Now().Truncate(time.Second))
+
+	c, err := counter.NewFlexibleHitCounter(time.Second*5, time.Second, counter.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		c.AddHit()
+	}
+	clock.Advance(time.Second)
+	c.AddHit()
+
+	snap, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error taking snapshot: %s", err)
+	}
+
+	restored, err := counter.NewFlexibleHitCounter(time.Second*5, time.Second, counter.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("unexpected error restoring snapshot: %s", err)
+	}
+
+	if got, want := restored.GetHits(), c.GetHits(); got != want {
+		t.Errorf("expected restored counter to have %d hits but got %d", want, got)
+	}
+}
+
+func TestFlexibleHitCounter_RestoreRejectsMismatchedConfig(t *testing.T) {
+	c, err := counter.NewFlexibleHitCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	snap, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error taking snapshot: %s", err)
+	}
+
+	other, err := counter.NewFlexibleHitCounter(time.Second*10, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := other.Restore(snap); err != counter.ErrSnapshotMismatch {
+		t.Errorf("expected %q but got %v", counter.ErrSnapshotMismatch, err)
+	}
+}
+
+func TestFlexibleHitCounter_RestoreRejectsInvalidData(t *testing.T) {
+	c, err := counter.NewFlexibleHitCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := c.Restore([]byte("not a snapshot")); err != counter.ErrInvalidSnapshot {
+		t.Errorf("expected %q but got %v", counter.ErrInvalidSnapshot, err)
+	}
+}
+
+func TestFlexibleHitCounter_PersistEvery(t *testing.T) {
+	c, err := counter.NewFlexibleHitCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	c.AddHit()
+
+	var buf bytes.Buffer
+	stop := c.PersistEvery(time.Millisecond, &buf)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+
+	if buf.Len() == 0 {
+		t.Error("expected PersistEvery to have written at least one snapshot")
+	}
+}
+
+func TestImmediateHitCounter_SnapshotRestore(t *testing.T) {
+	clock := counter.NewManualClock(time.Now().Truncate(time.Second))
+
+	c, err := counter.NewImmediateHitCounter(time.Second*5, time.Second, counter.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		c.AddHit()
+	}
+	clock.Advance(time.Second)
+	c.AddHit()
+
+	snap, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error taking snapshot: %s", err)
+	}
+
+	restored, err := counter.NewImmediateHitCounter(time.Second*5, time.Second, counter.WithClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := restored.Restore(snap); err != nil {
+		t.Fatalf("unexpected error restoring snapshot: %s", err)
+	}
+
+	if got, want := restored.GetHits(), c.GetHits(); got != want {
+		t.Errorf("expected restored counter to have %d hits but got %d", want, got)
+	}
+}
+
+func TestImmediateHitCounter_RestoreRejectsMismatchedConfig(t *testing.T) {
+	c, err := counter.NewImmediateHitCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	snap, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("unexpected error taking snapshot: %s", err)
+	}
+
+	other, err := counter.NewImmediateHitCounter(time.Second*10, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := other.Restore(snap); err != counter.ErrSnapshotMismatch {
+		t.Errorf("expected %q but got %v", counter.ErrSnapshotMismatch, err)
+	}
+}
+
+func TestImmediateHitCounter_RestoreRejectsInvalidData(t *testing.T) {
+	c, err := counter.NewImmediateHitCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	if err := c.Restore([]byte("not a snapshot")); err != counter.ErrInvalidSnapshot {
+		t.Errorf("expected %q but got %v", counter.ErrInvalidSnapshot, err)
+	}
+}
+
+func TestImmediateHitCounter_PersistEvery(t *testing.T) {
+	c, err := counter.NewImmediateHitCounter(time.Second*5, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error creating new counter: %s", err)
+	}
+	c.AddHit()
+
+	var buf bytes.Buffer
+	stop := c.PersistEvery(time.Millisecond, &buf)
+	time.Sleep(20 * time.Millisecond)
+	stop()
+
+	if buf.Len() == 0 {
+		t.Error("expected PersistEvery to have written at least one snapshot")
+	}
+}